@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Codec controls how records are serialized to and from disk. Drivers
+// default to JSONCodec but may be configured with any implementation via
+// Options.Codec, including a caller's own (CBOR, gob, msgpack, ...).
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+	Extension() string
+}
+
+// JSONCodec is the default Codec, matching the library's original on-disk
+// format.
+type JSONCodec struct{}
+
+func (JSONCodec) Marshal(v interface{}) ([]byte, error) {
+	b, err := json.MarshalIndent(v, "", "\t")
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
+
+func (JSONCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+func (JSONCodec) Extension() string {
+	return ".json"
+}
+
+// BSONCodec stores records as BSON, giving callers a compact binary
+// alternative to JSON without changing the Driver API.
+type BSONCodec struct{}
+
+func (BSONCodec) Marshal(v interface{}) ([]byte, error) {
+	return bson.Marshal(zeroEmptyJSONNumbers(v))
+}
+
+func (BSONCodec) Unmarshal(data []byte, v interface{}) error {
+	return bson.Unmarshal(data, v)
+}
+
+func (BSONCodec) Extension() string {
+	return ".bson"
+}
+
+var jsonNumberType = reflect.TypeOf(json.Number(""))
+
+// zeroEmptyJSONNumbers walks v and rewrites any zero-value json.Number
+// ("") to "0". encoding/json does the same internally before encoding a
+// Number, but mongo-driver's json.Number codec parses the raw string as a
+// float and errors on an empty one, so BSONCodec needs to apply the same
+// normalization itself. Everything that isn't a json.Number is returned
+// untouched (by value, not by reference) so Marshal never mutates the
+// caller's v.
+func zeroEmptyJSONNumbers(v interface{}) interface{} {
+	return sanitizeJSONNumbers(reflect.ValueOf(v)).Interface()
+}
+
+func sanitizeJSONNumbers(rv reflect.Value) reflect.Value {
+	if !rv.IsValid() {
+		return rv
+	}
+
+	if rv.Type() == jsonNumberType {
+		if rv.String() == "" {
+			return reflect.ValueOf(json.Number("0"))
+		}
+		return rv
+	}
+
+	switch rv.Kind() {
+	case reflect.Ptr:
+		if rv.IsNil() {
+			return rv
+		}
+		out := reflect.New(rv.Type().Elem())
+		out.Elem().Set(sanitizeJSONNumbers(rv.Elem()))
+		return out
+
+	case reflect.Interface:
+		if rv.IsNil() {
+			return rv
+		}
+		return sanitizeJSONNumbers(rv.Elem())
+
+	case reflect.Struct:
+		out := reflect.New(rv.Type()).Elem()
+		out.Set(rv) // keep unexported fields as they are
+		for i := 0; i < rv.NumField(); i++ {
+			if rv.Type().Field(i).PkgPath != "" {
+				continue // unexported
+			}
+			out.Field(i).Set(sanitizeJSONNumbers(rv.Field(i)))
+		}
+		return out
+
+	case reflect.Slice:
+		if rv.IsNil() {
+			return rv
+		}
+		out := reflect.MakeSlice(rv.Type(), rv.Len(), rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			out.Index(i).Set(sanitizeJSONNumbers(rv.Index(i)))
+		}
+		return out
+
+	case reflect.Array:
+		out := reflect.New(rv.Type()).Elem()
+		for i := 0; i < rv.Len(); i++ {
+			out.Index(i).Set(sanitizeJSONNumbers(rv.Index(i)))
+		}
+		return out
+
+	case reflect.Map:
+		if rv.IsNil() {
+			return rv
+		}
+		out := reflect.MakeMapWithSize(rv.Type(), rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			out.SetMapIndex(iter.Key(), sanitizeJSONNumbers(iter.Value()))
+		}
+		return out
+
+	default:
+		return rv
+	}
+}
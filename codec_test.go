@@ -0,0 +1,57 @@
+package main
+
+import "testing"
+
+// TestBSONCodecRoundTrip writes and reads a record through a
+// BSONCodec-configured Driver. bson.Marshal rejects an empty
+// encoding/json.Number, and Driver.Write used to append a trailing '\n'
+// that corrupted BSON's length-prefixed format, so this exercises both
+// fixes through the real Driver API rather than the codec in isolation.
+func TestBSONCodecRoundTrip(t *testing.T) {
+	db, err := New(t.TempDir(), &Options{Codec: BSONCodec{}})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	want := User{
+		Name:    "Robert",
+		Age:     "27",
+		Contact: "23344333",
+		Address: Address{City: "bangalore", State: "karnataka", Country: "india"},
+	}
+	if err := db.Write("users", want.Name, want); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	var got User
+	if err := db.Read("users", want.Name, &got); err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	// The unset Pincode json.Number is stored as 0, as encoding/json does.
+	want.Address.Pincode = "0"
+	if got != want {
+		t.Errorf("Read returned %+v, want %+v", got, want)
+	}
+}
+
+// TestBSONCodecRoundTripZeroValueNumber covers the original bug report: a
+// record with an unset json.Number field must not fail to marshal.
+func TestBSONCodecRoundTripZeroValueNumber(t *testing.T) {
+	db, err := New(t.TempDir(), &Options{Codec: BSONCodec{}})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	want := User{Name: "Neo"}
+	if err := db.Write("users", want.Name, want); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	var got User
+	if err := db.Read("users", want.Name, &got); err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if got.Name != want.Name {
+		t.Errorf("Read returned %+v, want %+v", got, want)
+	}
+}
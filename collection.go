@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Collection is a typed view over a single Driver collection. It removes
+// the unmarshal-into-struct boilerplate every caller of ReadAll used to
+// repeat by hand.
+type Collection[T any] struct {
+	driver     *Driver
+	collection string
+}
+
+// NewCollection returns a typed Collection backed by d, scoped to the
+// given collection name.
+func NewCollection[T any](d *Driver, collection string) *Collection[T] {
+	return &Collection[T]{driver: d, collection: collection}
+}
+
+// Get reads and unmarshals a single record by id.
+func (c *Collection[T]) Get(id string) (T, error) {
+	var v T
+	err := c.driver.Read(c.collection, id, &v)
+	return v, err
+}
+
+// Put writes v under id, replacing any existing record.
+func (c *Collection[T]) Put(id string, v T) error {
+	return c.driver.Write(c.collection, id, v)
+}
+
+// Delete removes the record stored under id.
+func (c *Collection[T]) Delete(id string) error {
+	return c.driver.Delete(c.collection, id)
+}
+
+// Find streams every record in the collection, unmarshals it into T, and
+// returns those for which pred returns true. Unlike ReadAll followed by a
+// manual unmarshal loop, each file is decoded exactly once.
+func (c *Collection[T]) Find(pred func(T) bool) ([]T, error) {
+	entries, err := c.recordEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	var results []T
+	for _, entry := range entries {
+		var v T
+		if err := c.decode(entry, &v); err != nil {
+			return nil, err
+		}
+		if pred(v) {
+			results = append(results, v)
+		}
+	}
+	return results, nil
+}
+
+// Page returns up to limit records starting at offset, ordered by resource
+// file name. Only the requested slice is decoded.
+func (c *Collection[T]) Page(offset, limit int) ([]T, error) {
+	if offset < 0 || limit < 0 {
+		return nil, fmt.Errorf("db: offset and limit must be non-negative")
+	}
+
+	entries, err := c.recordEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	if offset >= len(entries) {
+		return nil, nil
+	}
+	end := offset + limit
+	if end > len(entries) {
+		end = len(entries)
+	}
+
+	results := make([]T, 0, end-offset)
+	for _, entry := range entries[offset:end] {
+		var v T
+		if err := c.decode(entry, &v); err != nil {
+			return nil, err
+		}
+		results = append(results, v)
+	}
+	return results, nil
+}
+
+// Count returns the number of records in the collection without decoding
+// any of them.
+func (c *Collection[T]) Count() (int, error) {
+	entries, err := c.recordEntries()
+	if err != nil {
+		return 0, err
+	}
+	return len(entries), nil
+}
+
+// recordEntries lists the collection directory under a read lock and
+// filters it down to the files this Collection's codec actually wrote.
+// Listing only tells us which resources exist; decode still goes through
+// Driver.Read so each record is read under its own resource lock.
+func (c *Collection[T]) recordEntries() ([]os.DirEntry, error) {
+	collMutex := c.driver.getOrCreateCollectionMutex(c.collection)
+	collMutex.RLock()
+	defer collMutex.RUnlock()
+
+	dir := filepath.Join(c.driver.dir, c.collection)
+	all, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	ext := c.driver.codec.Extension()
+	entries := make([]os.DirEntry, 0, len(all))
+	for _, entry := range all {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ext {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// decode reads a single record through Driver.Read, which takes the
+// resource's lock, rather than reading the file directly.
+func (c *Collection[T]) decode(entry os.DirEntry, v *T) error {
+	ext := c.driver.codec.Extension()
+	resource := strings.TrimSuffix(entry.Name(), ext)
+	return c.driver.Read(c.collection, resource, v)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestCollectionGetPutDelete(t *testing.T) {
+	db, err := New(t.TempDir(), nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	users := NewCollection[User](db, "users")
+
+	if err := users.Put("paul", User{Name: "paul", Age: "25"}); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	got, err := users.Get("paul")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got.Name != "paul" || got.Age != "25" {
+		t.Errorf("Get returned %+v, want Name=paul Age=25", got)
+	}
+
+	if err := users.Delete("paul"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := users.Get("paul"); err == nil {
+		t.Fatal("Get after Delete: expected error")
+	}
+}
+
+func TestCollectionFindPageCount(t *testing.T) {
+	db, err := New(t.TempDir(), nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	users := NewCollection[User](db, "users")
+
+	names := []string{"albert", "neo", "paul", "robert", "vince"}
+	for i, name := range names {
+		age := "20"
+		if i%2 == 0 {
+			age = "30"
+		}
+		if err := users.Put(name, User{Name: name, Age: json.Number(age)}); err != nil {
+			t.Fatalf("Put(%s): %v", name, err)
+		}
+	}
+
+	count, err := users.Count()
+	if err != nil {
+		t.Fatalf("Count: %v", err)
+	}
+	if count != len(names) {
+		t.Errorf("Count = %d, want %d", count, len(names))
+	}
+
+	thirties, err := users.Find(func(u User) bool { return u.Age == "30" })
+	if err != nil {
+		t.Fatalf("Find: %v", err)
+	}
+	if len(thirties) != 3 {
+		t.Errorf("Find returned %d records, want 3", len(thirties))
+	}
+
+	page, err := users.Page(0, 2)
+	if err != nil {
+		t.Fatalf("Page: %v", err)
+	}
+	if len(page) != 2 {
+		t.Errorf("Page(0, 2) returned %d records, want 2", len(page))
+	}
+
+	page, err = users.Page(len(names), 2)
+	if err != nil {
+		t.Fatalf("Page past the end: %v", err)
+	}
+	if len(page) != 0 {
+		t.Errorf("Page past the end returned %d records, want 0", len(page))
+	}
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// IndexExtractor pulls the value to index out of a record's raw, encoded
+// bytes (the same bytes Driver.Write persists to disk).
+type IndexExtractor func(data []byte) (string, error)
+
+// driverIndex is a persistent, in-memory-cached mapping of extracted key ->
+// matching resource IDs for one collection/name pair.
+type driverIndex struct {
+	name    string
+	extract IndexExtractor
+
+	mu      sync.RWMutex
+	entries map[string][]string
+}
+
+func (d *Driver) indexDir(collection string) string {
+	return filepath.Join(d.dir, collection, "_indexes")
+}
+
+func (idx *driverIndex) path(dir string) string {
+	return filepath.Join(dir, idx.name+".idx")
+}
+
+// persist must be called with idx.mu held.
+func (idx *driverIndex) persist(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	b, err := json.MarshalIndent(idx.entries, "", "\t")
+	if err != nil {
+		return err
+	}
+	b = append(b, byte('\n'))
+
+	tmpPath := idx.path(dir) + ".tmp"
+	if err := os.WriteFile(tmpPath, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, idx.path(dir))
+}
+
+// CreateIndex builds a persistent secondary index over collection, keyed
+// by whatever extract returns for each record. Write and Delete keep it up
+// to date afterwards. Building the index takes an exclusive lock on the
+// collection, so concurrent reads/writes wait until it is ready.
+func (d *Driver) CreateIndex(collection, name string, extract IndexExtractor) error {
+	if collection == "" {
+		return fmt.Errorf("Missing collection - no place to index!")
+	}
+	if name == "" {
+		return fmt.Errorf("Missing index name")
+	}
+	if extract == nil {
+		return fmt.Errorf("Missing extract function")
+	}
+
+	collMutex := d.getOrCreateCollectionMutex(collection)
+	collMutex.Lock()
+	defer collMutex.Unlock()
+
+	idx := &driverIndex{name: name, extract: extract, entries: make(map[string][]string)}
+
+	dir := filepath.Join(d.dir, collection)
+	ext := d.codec.Extension()
+
+	files, err := os.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	for _, f := range files {
+		if f.IsDir() || filepath.Ext(f.Name()) != ext {
+			continue
+		}
+
+		b, err := os.ReadFile(filepath.Join(dir, f.Name()))
+		if err != nil {
+			return err
+		}
+
+		key, err := extract(b)
+		if err != nil {
+			return err
+		}
+
+		id := strings.TrimSuffix(f.Name(), ext)
+		idx.entries[key] = append(idx.entries[key], id)
+	}
+
+	if err := idx.persist(d.indexDir(collection)); err != nil {
+		return err
+	}
+
+	d.indexMutex.Lock()
+	if d.indexes[collection] == nil {
+		d.indexes[collection] = make(map[string]*driverIndex)
+	}
+	d.indexes[collection][name] = idx
+	d.indexMutex.Unlock()
+
+	return nil
+}
+
+// Lookup returns the resource IDs currently indexed under key.
+func (d *Driver) Lookup(collection, indexName, key string) ([]string, error) {
+	idx, err := d.getIndex(collection, indexName)
+	if err != nil {
+		return nil, err
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	ids := idx.entries[key]
+	out := make([]string, len(ids))
+	copy(out, ids)
+	return out, nil
+}
+
+// FindByIndex looks up the first resource matching key in indexName and
+// reads it into v, the same way Driver.Read does.
+func (d *Driver) FindByIndex(collection, indexName, key string, v interface{}) error {
+	ids, err := d.Lookup(collection, indexName, key)
+	if err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return fmt.Errorf("no record found in %s for %s=%q", collection, indexName, key)
+	}
+	return d.Read(collection, ids[0], v)
+}
+
+func (d *Driver) getIndex(collection, name string) (*driverIndex, error) {
+	d.indexMutex.RLock()
+	defer d.indexMutex.RUnlock()
+
+	byName, ok := d.indexes[collection]
+	if !ok {
+		return nil, fmt.Errorf("no indexes registered for collection %q", collection)
+	}
+	idx, ok := byName[name]
+	if !ok {
+		return nil, fmt.Errorf("index %q not found on collection %q", name, collection)
+	}
+	return idx, nil
+}
+
+// updateIndexes re-extracts resource's key in every index registered for
+// collection and repoints the index entries at it. Callers must already
+// hold the resource's write lock.
+func (d *Driver) updateIndexes(collection, resource string, data []byte) error {
+	d.indexMutex.RLock()
+	byName := d.indexes[collection]
+	d.indexMutex.RUnlock()
+
+	dir := d.indexDir(collection)
+	for _, idx := range byName {
+		key, err := idx.extract(data)
+		if err != nil {
+			return err
+		}
+
+		idx.mu.Lock()
+		removeResourceLocked(idx.entries, resource)
+		idx.entries[key] = append(idx.entries[key], resource)
+		err = idx.persist(dir)
+		idx.mu.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeFromIndexes drops resource from every index registered for
+// collection. Callers must already hold the resource's write lock.
+func (d *Driver) removeFromIndexes(collection, resource string) error {
+	d.indexMutex.RLock()
+	byName := d.indexes[collection]
+	d.indexMutex.RUnlock()
+
+	dir := d.indexDir(collection)
+	for _, idx := range byName {
+		idx.mu.Lock()
+		removeResourceLocked(idx.entries, resource)
+		err := idx.persist(dir)
+		idx.mu.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeResourceLocked strips resource out of every key's ID list,
+// dropping keys left with no IDs. Callers must hold the index's mutex.
+func removeResourceLocked(entries map[string][]string, resource string) {
+	for key, ids := range entries {
+		kept := ids[:0]
+		for _, id := range ids {
+			if id != resource {
+				kept = append(kept, id)
+			}
+		}
+		if len(kept) == 0 {
+			delete(entries, key)
+		} else {
+			entries[key] = kept
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import "testing"
+
+func cityIndexExtractor(data []byte) (string, error) {
+	var u User
+	if err := (JSONCodec{}).Unmarshal(data, &u); err != nil {
+		return "", err
+	}
+	return u.Address.City, nil
+}
+
+func TestIndexLookupAndFindByIndex(t *testing.T) {
+	db, err := New(t.TempDir(), nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	users := []User{
+		{Name: "john", Address: Address{City: "bangalore"}},
+		{Name: "robert", Address: Address{City: "bangalore"}},
+		{Name: "paul", Address: Address{City: "san francisco"}},
+	}
+	for _, u := range users {
+		if err := db.Write("users", u.Name, u); err != nil {
+			t.Fatalf("Write(%s): %v", u.Name, err)
+		}
+	}
+
+	if err := db.CreateIndex("users", "by_city", cityIndexExtractor); err != nil {
+		t.Fatalf("CreateIndex: %v", err)
+	}
+
+	ids, err := db.Lookup("users", "by_city", "bangalore")
+	if err != nil {
+		t.Fatalf("Lookup: %v", err)
+	}
+	if len(ids) != 2 {
+		t.Errorf("Lookup(bangalore) returned %v, want 2 ids", ids)
+	}
+
+	var got User
+	if err := db.FindByIndex("users", "by_city", "san francisco", &got); err != nil {
+		t.Fatalf("FindByIndex: %v", err)
+	}
+	if got.Name != "paul" {
+		t.Errorf("FindByIndex(san francisco) = %+v, want paul", got)
+	}
+}
+
+func TestIndexStaysInSyncOnWriteAndDelete(t *testing.T) {
+	db, err := New(t.TempDir(), nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	if err := db.Write("users", "john", User{Name: "john", Address: Address{City: "bangalore"}}); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := db.CreateIndex("users", "by_city", cityIndexExtractor); err != nil {
+		t.Fatalf("CreateIndex: %v", err)
+	}
+
+	// Moving john to a new city must drop the stale "bangalore" entry.
+	if err := db.Write("users", "john", User{Name: "john", Address: Address{City: "mumbai"}}); err != nil {
+		t.Fatalf("Write (move city): %v", err)
+	}
+	if ids, err := db.Lookup("users", "by_city", "bangalore"); err != nil || len(ids) != 0 {
+		t.Errorf("Lookup(bangalore) after move = %v, %v, want no ids", ids, err)
+	}
+	if ids, err := db.Lookup("users", "by_city", "mumbai"); err != nil || len(ids) != 1 {
+		t.Errorf("Lookup(mumbai) after move = %v, %v, want 1 id", ids, err)
+	}
+
+	if err := db.Delete("users", "john"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if ids, err := db.Lookup("users", "by_city", "mumbai"); err != nil || len(ids) != 0 {
+		t.Errorf("Lookup(mumbai) after delete = %v, %v, want no ids", ids, err)
+	}
+}
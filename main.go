@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 
 	"github.com/charmbracelet/log"
@@ -36,15 +37,20 @@ type (
 	}
 
 	Driver struct {
-		mutex   sync.Mutex
-		mutexex map[string]*sync.Mutex
-		dir     string
-		log     Logger
+		mutex           sync.RWMutex
+		mutexes         map[string]*sync.RWMutex
+		resourceMutexes map[string]map[string]*sync.RWMutex
+		dir             string
+		log             Logger
+		codec           Codec
+		indexMutex      sync.RWMutex
+		indexes         map[string]map[string]*driverIndex
 	}
 )
 
 type Options struct {
 	Logger Logger
+	Codec  Codec
 }
 
 func New(dir string, options *Options) (*Driver, error) {
@@ -60,10 +66,17 @@ func New(dir string, options *Options) (*Driver, error) {
 		opts.Logger = lumber.NewConsoleLogger((lumber.INFO))
 	}
 
+	if opts.Codec == nil {
+		opts.Codec = JSONCodec{}
+	}
+
 	driver := Driver{
-		dir:     dir,
-		log:     opts.Logger,
-		mutexex: make(map[string]*sync.Mutex),
+		dir:             dir,
+		log:             opts.Logger,
+		codec:           opts.Codec,
+		mutexes:         make(map[string]*sync.RWMutex),
+		resourceMutexes: make(map[string]map[string]*sync.RWMutex),
+		indexes:         make(map[string]map[string]*driverIndex),
 	}
 
 	if _, err := os.Stat(dir); err == nil {
@@ -84,30 +97,36 @@ func (d *Driver) Write(collection, resource string, v interface{}) error {
 		return fmt.Errorf("Missing resource - unable to save record (no name)!")
 	}
 
-	mutex := d.getOrCreateMutex(collection)
-	mutex.Lock()
-	defer mutex.Unlock()
+	collMutex := d.getOrCreateCollectionMutex(collection)
+	collMutex.RLock()
+	defer collMutex.RUnlock()
+
+	resMutex := d.getOrCreateResourceMutex(collection, resource)
+	resMutex.Lock()
+	defer resMutex.Unlock()
 
 	dir := filepath.Join(d.dir, collection)
-	fnlPath := filepath.Join(dir, resource+".json")
+	fnlPath := filepath.Join(dir, resource+d.codec.Extension())
 	tmpPath := fnlPath + ".tmp"
 
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return err
 	}
 
-	b, err := json.MarshalIndent(v, "", "\t")
+	b, err := d.codec.Marshal(v)
 	if err != nil {
 		return err
 	}
 
-	b = append(b, byte('\n'))
-
 	if err := os.WriteFile(tmpPath, b, 0644); err != nil {
 		return err
 	}
 
-	return os.Rename(tmpPath, fnlPath)
+	if err := os.Rename(tmpPath, fnlPath); err != nil {
+		return err
+	}
+
+	return d.updateIndexes(collection, resource, b)
 }
 
 func (d *Driver) Read(collection, resource string, v interface{}) error {
@@ -118,17 +137,25 @@ func (d *Driver) Read(collection, resource string, v interface{}) error {
 		return fmt.Errorf("Missing resource - unable to save record")
 	}
 
+	collMutex := d.getOrCreateCollectionMutex(collection)
+	collMutex.RLock()
+	defer collMutex.RUnlock()
+
+	resMutex := d.getOrCreateResourceMutex(collection, resource)
+	resMutex.RLock()
+	defer resMutex.RUnlock()
+
 	record := filepath.Join(d.dir, collection, resource)
-	if _, err := stat(record); err != nil {
+	if _, err := stat(record, d.codec.Extension()); err != nil {
 		return err
 	}
 
-	b, err := os.ReadFile(record + ".json")
+	b, err := os.ReadFile(record + d.codec.Extension())
 	if err != nil {
 		return err
 	}
 
-	return json.Unmarshal(b, &v)
+	return d.codec.Unmarshal(b, v)
 }
 
 func (d *Driver) ReadAll(collection string) ([]string, error) {
@@ -136,15 +163,28 @@ func (d *Driver) ReadAll(collection string) ([]string, error) {
 		return nil, fmt.Errorf("Missing collection")
 	}
 
+	collMutex := d.getOrCreateCollectionMutex(collection)
+	collMutex.RLock()
+	defer collMutex.RUnlock()
+
 	dir := filepath.Join(d.dir, collection)
-	if _, err := stat(dir); err != nil {
+	if _, err := stat(dir, d.codec.Extension()); err != nil {
 		return nil, err
 	}
 
 	files, _ := os.ReadDir(dir)
+	ext := d.codec.Extension()
 	var ans []string
 	for _, c := range files {
+		if c.IsDir() || filepath.Ext(c.Name()) != ext {
+			continue
+		}
+
+		resource := strings.TrimSuffix(c.Name(), ext)
+		resMutex := d.getOrCreateResourceMutex(collection, resource)
+		resMutex.RLock()
 		b, err := os.ReadFile(filepath.Join(dir, c.Name()))
+		resMutex.RUnlock()
 		if err != nil {
 			return nil, err
 		}
@@ -156,39 +196,93 @@ func (d *Driver) ReadAll(collection string) ([]string, error) {
 func (d *Driver) Delete(collection, resource string) error {
 
 	dir := filepath.Join(d.dir, collection)
-	mutex := d.getOrCreateMutex(collection)
-	mutex.Lock()
-	defer mutex.Unlock()
+	record := filepath.Join(dir, resource)
 
-	switch fi, err := stat(dir); {
+	collMutex := d.getOrCreateCollectionMutex(collection)
+	collMutex.RLock()
+	defer collMutex.RUnlock()
+
+	resMutex := d.getOrCreateResourceMutex(collection, resource)
+	resMutex.Lock()
+	defer resMutex.Unlock()
+
+	switch fi, err := stat(record, d.codec.Extension()); {
 	case fi == nil && err != nil:
 		{
 			return err
 		}
 	case fi.Mode().IsDir():
 		{
-			return os.Remove(dir)
+			return os.Remove(record)
 		}
 	case fi.Mode().IsRegular():
 		{
-			return os.Remove(dir + ".json")
+			if err := os.Remove(record + d.codec.Extension()); err != nil {
+				return err
+			}
+			return d.removeFromIndexes(collection, resource)
 		}
 	}
 	return nil
 }
 
-func (d *Driver) getOrCreateMutex(collection string) *sync.Mutex {
-	m, ok := d.mutexex[collection]
+// getOrCreateCollectionMutex returns the RWMutex guarding an entire
+// collection. Read/ReadAll take a read lock on it so they can run
+// concurrently with each other; Write/Delete take a read lock too, relying
+// on the finer-grained per-resource mutex below to serialize conflicting
+// writes, so concurrent writes to different resources in the same
+// collection never block on each other.
+func (d *Driver) getOrCreateCollectionMutex(collection string) *sync.RWMutex {
+	d.mutex.RLock()
+	m, ok := d.mutexes[collection]
+	d.mutex.RUnlock()
+	if ok {
+		return m
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if m, ok = d.mutexes[collection]; ok {
+		return m
+	}
+	m = &sync.RWMutex{}
+	d.mutexes[collection] = m
+	return m
+}
+
+// getOrCreateResourceMutex returns the RWMutex guarding a single resource
+// within a collection.
+func (d *Driver) getOrCreateResourceMutex(collection, resource string) *sync.RWMutex {
+	d.mutex.RLock()
+	resources, ok := d.resourceMutexes[collection]
+	if ok {
+		m, ok := resources[resource]
+		d.mutex.RUnlock()
+		if ok {
+			return m
+		}
+	} else {
+		d.mutex.RUnlock()
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	resources, ok = d.resourceMutexes[collection]
+	if !ok {
+		resources = make(map[string]*sync.RWMutex)
+		d.resourceMutexes[collection] = resources
+	}
+	m, ok := resources[resource]
 	if !ok {
-		m = &sync.Mutex{}
-		d.mutexex[collection] = m
+		m = &sync.RWMutex{}
+		resources[resource] = m
 	}
-	return d.mutexex[collection]
+	return m
 }
 
-func stat(path string) (fi os.FileInfo, err error) {
+func stat(path, ext string) (fi os.FileInfo, err error) {
 	if fi, err = os.Stat(path); os.IsNotExist(err) {
-		fi, err = os.Stat(path + ".json")
+		fi, err = os.Stat(path + ext)
 	}
 	return
 }
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// TestConcurrentWritesAcrossCollections proves that writes/reads against
+// distinct collections (and distinct resources within a collection) no
+// longer race on the shared mutex map. Run with `go test -race`.
+func TestConcurrentWritesAcrossCollections(t *testing.T) {
+	db, err := New(t.TempDir(), nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	const collections = 8
+	const resourcesPerCollection = 16
+
+	var wg sync.WaitGroup
+	for c := 0; c < collections; c++ {
+		collection := fmt.Sprintf("collection-%d", c)
+		for r := 0; r < resourcesPerCollection; r++ {
+			resource := fmt.Sprintf("resource-%d", r)
+			wg.Add(1)
+			go func(collection, resource string, id int) {
+				defer wg.Done()
+				age := json.Number(fmt.Sprintf("%d", id))
+				if err := db.Write(collection, resource, User{Name: resource, Age: age}); err != nil {
+					t.Errorf("Write(%s, %s): %v", collection, resource, err)
+				}
+
+				var u User
+				if err := db.Read(collection, resource, &u); err != nil {
+					t.Errorf("Read(%s, %s): %v", collection, resource, err)
+				}
+			}(collection, resource, r)
+		}
+	}
+	wg.Wait()
+
+	for c := 0; c < collections; c++ {
+		collection := fmt.Sprintf("collection-%d", c)
+		records, err := db.ReadAll(collection)
+		if err != nil {
+			t.Fatalf("ReadAll(%s): %v", collection, err)
+		}
+		if len(records) != resourcesPerCollection {
+			t.Errorf("ReadAll(%s) returned %d records, want %d", collection, len(records), resourcesPerCollection)
+		}
+	}
+}
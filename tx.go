@@ -0,0 +1,193 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+type txOpKind int
+
+const (
+	txOpWrite txOpKind = iota
+	txOpDelete
+)
+
+type txOp struct {
+	kind       txOpKind
+	collection string
+	resource   string
+	value      interface{}
+}
+
+// Tx buffers Write/Delete operations, possibly spanning multiple
+// collections and resources, and commits them atomically. Obtain one with
+// Driver.Begin.
+type Tx struct {
+	driver *Driver
+	ops    []txOp
+}
+
+// Begin starts a new transaction. Buffered operations have no effect on
+// the database until Commit succeeds.
+func (d *Driver) Begin() *Tx {
+	return &Tx{driver: d}
+}
+
+// Write stages a record write for commit.
+func (tx *Tx) Write(collection, resource string, v interface{}) {
+	tx.ops = append(tx.ops, txOp{kind: txOpWrite, collection: collection, resource: resource, value: v})
+}
+
+// Delete stages a record deletion for commit.
+func (tx *Tx) Delete(collection, resource string) {
+	tx.ops = append(tx.ops, txOp{kind: txOpDelete, collection: collection, resource: resource})
+}
+
+type txLockKey struct {
+	collection string
+	resource   string
+}
+
+// Commit acquires every lock the transaction needs in a canonical order
+// (sorted by collection then resource) so that concurrent transactions can
+// never deadlock against each other, stages all writes to *.tmp files and
+// fsyncs them, then renames the temp files into place and removes any
+// deleted resources. If staging any write fails, the temp files written so
+// far are removed and the finals are left untouched.
+func (tx *Tx) Commit() (err error) {
+	if len(tx.ops) == 0 {
+		return nil
+	}
+
+	keys := tx.lockKeys()
+	for _, k := range keys {
+		collMutex := tx.driver.getOrCreateCollectionMutex(k.collection)
+		collMutex.RLock()
+		defer collMutex.RUnlock()
+	}
+	for _, k := range keys {
+		resMutex := tx.driver.getOrCreateResourceMutex(k.collection, k.resource)
+		resMutex.Lock()
+		defer resMutex.Unlock()
+	}
+
+	staged := make([]string, 0, len(tx.ops))
+	data := make([][]byte, len(tx.ops))
+	defer func() {
+		if err != nil {
+			for _, tmpPath := range staged {
+				os.Remove(tmpPath)
+			}
+		}
+	}()
+
+	for i, op := range tx.ops {
+		if op.kind != txOpWrite {
+			continue
+		}
+
+		dir := filepath.Join(tx.driver.dir, op.collection)
+		if err = os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+
+		tmpPath := tx.tmpPath(op)
+
+		b, merr := tx.driver.codec.Marshal(op.value)
+		if merr != nil {
+			err = merr
+			return err
+		}
+
+		f, oerr := os.Create(tmpPath)
+		if oerr != nil {
+			err = oerr
+			return err
+		}
+		staged = append(staged, tmpPath)
+		if _, err = f.Write(b); err != nil {
+			f.Close()
+			return err
+		}
+		if err = f.Sync(); err != nil {
+			f.Close()
+			return err
+		}
+		if err = f.Close(); err != nil {
+			return err
+		}
+
+		data[i] = b
+	}
+
+	for i, op := range tx.ops {
+		switch op.kind {
+		case txOpWrite:
+			if err = os.Rename(tx.tmpPath(op), tx.fnlPath(op)); err != nil {
+				return err
+			}
+			if err = tx.driver.updateIndexes(op.collection, op.resource, data[i]); err != nil {
+				return err
+			}
+		case txOpDelete:
+			if err = os.Remove(tx.fnlPath(op)); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			err = nil
+			if err = tx.driver.removeFromIndexes(op.collection, op.resource); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Rollback discards the transaction and removes any temp files a prior,
+// failed Commit call may have left behind.
+func (tx *Tx) Rollback() error {
+	var firstErr error
+	for _, op := range tx.ops {
+		if op.kind != txOpWrite {
+			continue
+		}
+		if err := os.Remove(tx.tmpPath(op)); err != nil && !os.IsNotExist(err) && firstErr == nil {
+			firstErr = err
+		}
+	}
+	tx.ops = nil
+	return firstErr
+}
+
+func (tx *Tx) fnlPath(op txOp) string {
+	return filepath.Join(tx.driver.dir, op.collection, op.resource+tx.driver.codec.Extension())
+}
+
+func (tx *Tx) tmpPath(op txOp) string {
+	return tx.fnlPath(op) + ".tmp"
+}
+
+// lockKeys returns the distinct (collection, resource) pairs touched by
+// the transaction in a stable, deterministic order.
+func (tx *Tx) lockKeys() []txLockKey {
+	seen := make(map[txLockKey]struct{}, len(tx.ops))
+	keys := make([]txLockKey, 0, len(tx.ops))
+	for _, op := range tx.ops {
+		k := txLockKey{collection: op.collection, resource: op.resource}
+		if _, ok := seen[k]; ok {
+			continue
+		}
+		seen[k] = struct{}{}
+		keys = append(keys, k)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].collection != keys[j].collection {
+			return keys[i].collection < keys[j].collection
+		}
+		return keys[i].resource < keys[j].resource
+	})
+
+	return keys
+}
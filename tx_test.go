@@ -0,0 +1,128 @@
+package main
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestTxCommit writes and deletes across two different collections in a
+// single transaction and checks both land atomically once Commit returns.
+func TestTxCommit(t *testing.T) {
+	db, err := New(t.TempDir(), nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	if err := db.Write("users", "paul", User{Name: "paul"}); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	tx := db.Begin()
+	tx.Write("users", "robert", User{Name: "robert"})
+	tx.Write("accounts", "robert", User{Name: "robert"})
+	tx.Delete("users", "paul")
+
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+
+	var u User
+	if err := db.Read("users", "robert", &u); err != nil {
+		t.Fatalf("Read(users, robert): %v", err)
+	}
+	if err := db.Read("accounts", "robert", &u); err != nil {
+		t.Fatalf("Read(accounts, robert): %v", err)
+	}
+	if err := db.Read("users", "paul", &u); err == nil {
+		t.Fatalf("Read(users, paul): expected error, record should have been deleted by the committed Tx")
+	}
+}
+
+// TestTxCommitStagingFailureLeavesFinalsUntouched verifies that when
+// staging fails partway through, none of the transaction's operations are
+// applied: the record staged before the failure is not readable and the
+// collection holds no records.
+func TestTxCommitStagingFailureLeavesFinalsUntouched(t *testing.T) {
+	db, err := New(t.TempDir(), nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	tx := db.Begin()
+	tx.Write("users", "robert", User{Name: "robert"})
+	tx.Write("users", "bad", unmarshalableValue{})
+
+	if err := tx.Commit(); err == nil {
+		t.Fatal("Commit: expected error from the unmarshalable value")
+	}
+
+	var u User
+	if err := db.Read("users", "robert", &u); err == nil {
+		t.Fatal("Read(users, robert): expected error, Commit should not have applied any op after staging failed")
+	}
+	records, err := db.ReadAll("users")
+	if err != nil {
+		t.Fatalf("ReadAll(users): %v", err)
+	}
+	if len(records) != 0 {
+		t.Fatalf("ReadAll(users) returned %d records, want 0 after a fully failed Commit", len(records))
+	}
+}
+
+// TestTxCommitLockOrderingAvoidsDeadlock runs two transactions
+// concurrently that touch the same two resources in opposite orders. If
+// Commit locked them in the order each Tx happened to buffer them rather
+// than a canonical order, this would deadlock.
+func TestTxCommitLockOrderingAvoidsDeadlock(t *testing.T) {
+	db, err := New(t.TempDir(), nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	go func() {
+		defer wg.Done()
+		tx := db.Begin()
+		tx.Write("users", "alice", User{Name: "alice"})
+		tx.Write("users", "bob", User{Name: "bob"})
+		if err := tx.Commit(); err != nil {
+			t.Errorf("Commit (alice, bob): %v", err)
+		}
+	}()
+
+	go func() {
+		defer wg.Done()
+		tx := db.Begin()
+		tx.Write("users", "bob", User{Name: "bob"})
+		tx.Write("users", "alice", User{Name: "alice"})
+		if err := tx.Commit(); err != nil {
+			t.Errorf("Commit (bob, alice): %v", err)
+		}
+	}()
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Commit calls deadlocked")
+	}
+}
+
+// unmarshalableValue always fails to JSON-marshal, forcing Tx.Commit's
+// staging loop to fail.
+type unmarshalableValue struct{}
+
+func (unmarshalableValue) MarshalJSON() ([]byte, error) {
+	return nil, errUnmarshalable
+}
+
+var errUnmarshalable = errors.New("cannot marshal unmarshalableValue")
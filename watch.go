@@ -0,0 +1,178 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Op describes the kind of change a Watch event reports. Watch and WatchAll
+// report both new and changed records as Update and removed ones as
+// Delete; they never emit Create.
+type Op int
+
+const (
+	Create Op = iota
+	Update
+	Delete
+)
+
+// Event is delivered on the channel returned by Watch/WatchAll whenever a
+// record changes.
+type Event struct {
+	Op         Op
+	Collection string
+	Resource   string
+	Data       []byte
+}
+
+// Watch subscribes to changes in collection. Write's tmp-then-rename
+// sequence is collapsed into a single Update event; the transient .tmp
+// file never reaches the channel. Call the returned cancel func to stop
+// watching and release the underlying fsnotify watcher.
+func (d *Driver) Watch(collection string) (<-chan Event, func(), error) {
+	dir := filepath.Join(d.dir, collection)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, nil, err
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := watcher.Add(dir); err != nil {
+		watcher.Close()
+		return nil, nil, err
+	}
+
+	events := make(chan Event)
+	done := make(chan struct{})
+
+	go d.watchLoop(watcher, map[string]string{dir: collection}, events, done)
+
+	cancel := func() {
+		close(done)
+		watcher.Close()
+	}
+	return events, cancel, nil
+}
+
+// WatchAll subscribes to changes across every collection, including ones
+// created after the call. Call the returned cancel func to stop watching.
+func (d *Driver) WatchAll() (<-chan Event, func(), error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := os.MkdirAll(d.dir, 0755); err != nil {
+		watcher.Close()
+		return nil, nil, err
+	}
+	if err := watcher.Add(d.dir); err != nil {
+		watcher.Close()
+		return nil, nil, err
+	}
+
+	collections := map[string]string{}
+	entries, err := os.ReadDir(d.dir)
+	if err != nil && !os.IsNotExist(err) {
+		watcher.Close()
+		return nil, nil, err
+	}
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		dir := filepath.Join(d.dir, e.Name())
+		if err := watcher.Add(dir); err != nil {
+			watcher.Close()
+			return nil, nil, err
+		}
+		collections[dir] = e.Name()
+	}
+
+	events := make(chan Event)
+	done := make(chan struct{})
+
+	go d.watchLoop(watcher, collections, events, done)
+
+	cancel := func() {
+		close(done)
+		watcher.Close()
+	}
+	return events, cancel, nil
+}
+
+// watchLoop translates raw fsnotify events on the watched collection
+// directories into Events. dirs maps a watched directory to the
+// collection name it holds; watchLoop adds new entries to it as new
+// collection directories show up (only relevant for WatchAll, where the
+// top-level database directory is also watched).
+func (d *Driver) watchLoop(watcher *fsnotify.Watcher, dirs map[string]string, events chan<- Event, done <-chan struct{}) {
+	defer close(events)
+	ext := d.codec.Extension()
+
+	for {
+		select {
+		case <-done:
+			return
+
+		case ev, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+
+			dir, name := filepath.Split(ev.Name)
+			dir = filepath.Clean(dir)
+
+			if collection, watched := dirs[dir]; watched {
+				d.emitRecordEvent(ev, collection, name, ext, events, done)
+				continue
+			}
+
+			if ev.Op&fsnotify.Create != 0 {
+				if fi, err := os.Stat(ev.Name); err == nil && fi.IsDir() {
+					if err := watcher.Add(ev.Name); err == nil {
+						dirs[ev.Name] = filepath.Base(ev.Name)
+					}
+				}
+			}
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			d.log.Error(err.Error())
+		}
+	}
+}
+
+func (d *Driver) emitRecordEvent(ev fsnotify.Event, collection, name, ext string, events chan<- Event, done <-chan struct{}) {
+	if filepath.Ext(name) != ext {
+		return
+	}
+	resource := strings.TrimSuffix(name, ext)
+
+	var op Op
+	switch {
+	case ev.Op&(fsnotify.Remove|fsnotify.Rename) != 0:
+		op = Delete
+	case ev.Op&(fsnotify.Create|fsnotify.Write) != 0:
+		op = Update
+	default:
+		return
+	}
+
+	var data []byte
+	if op != Delete {
+		data, _ = os.ReadFile(ev.Name)
+	}
+
+	select {
+	case events <- Event{Op: op, Collection: collection, Resource: resource, Data: data}:
+	case <-done:
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatchReportsUpdateAndDelete(t *testing.T) {
+	db, err := New(t.TempDir(), nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	events, cancel, err := db.Watch("users")
+	if err != nil {
+		t.Fatalf("Watch: %v", err)
+	}
+	defer cancel()
+
+	if err := db.Write("users", "paul", User{Name: "paul"}); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	ev := waitForEvent(t, events)
+	if ev.Op != Update || ev.Collection != "users" || ev.Resource != "paul" {
+		t.Fatalf("got %+v, want an Update event for users/paul", ev)
+	}
+
+	if err := db.Delete("users", "paul"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	ev = waitForEvent(t, events)
+	if ev.Op != Delete || ev.Resource != "paul" {
+		t.Fatalf("got %+v, want a Delete event for paul", ev)
+	}
+}
+
+func TestWatchAllCoversEveryCollection(t *testing.T) {
+	dir := t.TempDir()
+	// Pre-create both collection directories so WatchAll's initial scan
+	// picks them both up before any writes happen.
+	if err := os.MkdirAll(filepath.Join(dir, "users"), 0755); err != nil {
+		t.Fatalf("MkdirAll(users): %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "accounts"), 0755); err != nil {
+		t.Fatalf("MkdirAll(accounts): %v", err)
+	}
+
+	db, err := New(dir, nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	events, cancel, err := db.WatchAll()
+	if err != nil {
+		t.Fatalf("WatchAll: %v", err)
+	}
+	defer cancel()
+
+	if err := db.Write("users", "paul", User{Name: "paul"}); err != nil {
+		t.Fatalf("Write(users): %v", err)
+	}
+	if err := db.Write("accounts", "robert", User{Name: "robert"}); err != nil {
+		t.Fatalf("Write(accounts): %v", err)
+	}
+
+	seen := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		ev := waitForEvent(t, events)
+		if ev.Op != Update {
+			t.Fatalf("got %+v, want an Update event", ev)
+		}
+		seen[ev.Collection+"/"+ev.Resource] = true
+	}
+
+	if !seen["users/paul"] || !seen["accounts/robert"] {
+		t.Fatalf("got events %v, want users/paul and accounts/robert", seen)
+	}
+}
+
+func waitForEvent(t *testing.T, events <-chan Event) Event {
+	t.Helper()
+	select {
+	case ev, ok := <-events:
+		if !ok {
+			t.Fatal("events channel closed unexpectedly")
+		}
+		return ev
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a watch event")
+		return Event{}
+	}
+}